@@ -5,20 +5,35 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"strings"
 
-	"github.com/sourcegraph/go-vcs/vcs"
+	"golang.org/x/sync/singleflight"
+
 	"github.com/sourcegraph/srclib/buildstore"
 	"github.com/sourcegraph/srclib/config"
 	"github.com/sourcegraph/srclib/repo"
 	"github.com/sourcegraph/srclib/scan"
+	"github.com/sourcegraph/srclib/vcs"
+)
+
+// repoRootGroup and cloneURLGroup collapse concurrent NewRepoContext calls
+// for the same directory into a single repo-root detection and a single
+// clone-URL lookup; the commit ID is still resolved on every call. This is a
+// hot path: editor integrations and CI call srclib (and thus
+// NewRepoContext) repeatedly in quick succession for the same repo.
+var (
+	repoRootGroup singleflight.Group
+	cloneURLGroup singleflight.Group
 )
 
+type repoRoot struct {
+	vcsCmd *vcs.Cmd
+	dir    string
+}
+
 type RepoContext struct {
 	RepoRootDir string // Root directory containing repository being analyzed
-	VCSType     string // VCS type (git or hg)
+	VCSType     string // VCS type (git, hg, svn, or bzr)
 	CommitID    string // CommitID of current working directory
 	CloneURL    string // CloneURL of repo.
 }
@@ -30,41 +45,43 @@ func NewRepoContext(targetDir string) (*RepoContext, error) {
 		return nil, fmt.Errorf("directory not exist: %q", targetDir)
 	}
 
-	// VCS and root directory
-	rc := new(RepoContext)
-	for _, vcsType := range []string{"git", "hg"} {
-		if d, err := getRepoRootDir(vcsType, targetDir); err == nil {
-			rc.VCSType = vcsType
-			rc.RepoRootDir = d
-			break
+	// VCS and root directory, detected by walking up from targetDir looking
+	// for a registered VCS backend's root marker (e.g. ".git", ".hg").
+	rrv, err, _ := repoRootGroup.Do(targetDir, func() (interface{}, error) {
+		vcsCmd, rootDir, err := vcs.FromDir(targetDir, "")
+		if err != nil {
+			return nil, err
 		}
-	}
-	if rc.RepoRootDir == "" {
-		return nil, fmt.Errorf("warning: failed to detect repository root dir for %q", targetDir)
-	}
-
-	// Determine current working tree commit ID.
-	repo, err := vcs.Open(rc.VCSType, rc.RepoRootDir)
+		return &repoRoot{vcsCmd: vcsCmd, dir: rootDir}, nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	var currentRevSpec string
-	switch rc.VCSType {
-	case "git":
-		currentRevSpec = "HEAD"
-	case "hg":
-		currentRevSpec = "tip"
-	}
-	currentCommitID, err := repo.ResolveRevision(currentRevSpec)
+	rr := rrv.(*repoRoot)
+	rc := &RepoContext{VCSType: rr.vcsCmd.Name, RepoRootDir: rr.dir}
+
+	// Determine current working tree commit ID.
+	currentCommitID, err := rr.vcsCmd.Revision(rc.RepoRootDir)
 	if err != nil {
 		return nil, err
 	}
-
-	rc.CommitID = string(currentCommitID)
+	rc.CommitID = currentCommitID
 
 	// get default URI (if URI is not specified in .sourcegraph file)
-	cloneURL, err := getVCSCloneURL(rc.VCSType, rc.RepoRootDir)
+	cloneURLKey := rc.VCSType + "\x00" + rc.RepoRootDir
+	cuv, err, _ := cloneURLGroup.Do(cloneURLKey, func() (interface{}, error) {
+		return rr.vcsCmd.RemoteRepoURL(rc.RepoRootDir)
+	})
 	if err != nil {
+		return nil, fmt.Errorf("could not get VCS URL: %s", err)
+	}
+	cloneURL := cuv.(string)
+	if err := vcs.CheckSecure(cloneURL); err != nil {
+		// srclib will run toolchain code against whatever this clone URL
+		// resolves to, so a plaintext scheme (e.g. a rewritten
+		// "git@github.com:" -> "git://github.com/") is a silent downgrade,
+		// not just a slow transport. Let the caller decide whether to
+		// proceed, instead of defaulting to fetching over it.
 		return nil, err
 	}
 	rc.CloneURL = cloneURL
@@ -152,46 +169,3 @@ func getConfigFile(repoDir, commitID string) (string, error) {
 	}
 	return filepath.Join(rootDataDir, repoStore.CommitPath(commitID), buildstore.CachedRepositoryConfigFilename), nil
 }
-
-func getRepoRootDir(vcsType string, dir string) (string, error) {
-	var cmd *exec.Cmd
-	switch vcsType {
-	case "git":
-		cmd = exec.Command("git", "rev-parse", "--show-toplevel")
-	case "hg":
-		cmd = exec.Command("hg", "root")
-	}
-	if cmd == nil {
-		return "", fmt.Errorf("unrecognized VCS %v", vcsType)
-	}
-	cmd.Dir = dir
-	out, err := cmd.Output()
-	if err != nil {
-		return "", err
-	}
-	return strings.TrimSpace(string(out)), nil
-}
-
-func getVCSCloneURL(vcsType string, repoDir string) (string, error) {
-	var cmd *exec.Cmd
-	switch vcsType {
-	case "git":
-		cmd = exec.Command("git", "config", "remote.origin.url")
-	case "hg":
-		cmd = exec.Command("hg", "paths", "default")
-	}
-	if cmd == nil {
-		return "", fmt.Errorf("unrecognized VCS %v", vcsType)
-	}
-	cmd.Dir = repoDir
-	out, err := cmd.Output()
-	if err != nil {
-		return "", fmt.Errorf("could not get VCS URL: %s", err)
-	}
-
-	cloneURL := strings.TrimSpace(string(out))
-	if vcsType == "git" {
-		cloneURL = strings.Replace(cloneURL, "git@github.com:", "git://github.com/", 1)
-	}
-	return cloneURL, nil
-}
@@ -0,0 +1,127 @@
+package toolchain
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// lookupGroup and listGroup collapse concurrent Lookup/List calls for the
+// same key into a single underlying disk access. This matters because
+// editors and CI invoke srclib repeatedly in short succession, and both
+// operations otherwise re-walk SrclibPath on every call.
+var (
+	lookupGroup singleflight.Group
+	listGroup   singleflight.Group
+)
+
+// cacheTTL bounds how long a cached toolchains.json is trusted without
+// re-walking SrclibPath. Toolchains are installed and removed several
+// directories below a SrclibPath entry (e.g.
+// $SRCLIBPATH/github.com/user/repo/Srclibtoolchain), so no single mtime
+// check above that depth reliably detects every addition or removal; a
+// short TTL bounds the staleness window instead. It is long enough to
+// collapse the back-to-back calls a single editor/CI invocation of srclib
+// makes, and short enough that a toolchain installed or removed by hand
+// becomes visible again well within one interactive session.
+const cacheTTL = 2 * time.Second
+
+// cacheEntry is the on-disk representation of an *Info.
+type cacheEntry struct {
+	Info Info
+}
+
+// toolchainCache is the schema of $SRCLIBPATH[0]/.cache/toolchains.json.
+type toolchainCache struct {
+	BuiltAt time.Time
+	Entries []cacheEntry
+}
+
+func cacheFilePath() (string, error) {
+	dirs := strings.Split(SrclibPath, ":")
+	if len(dirs) == 0 || dirs[0] == "" {
+		return "", os.ErrNotExist
+	}
+	return filepath.Join(dirs[0], ".cache", "toolchains.json"), nil
+}
+
+func readCache() (*toolchainCache, error) {
+	path, err := cacheFilePath()
+	if err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c toolchainCache
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func writeCache(c *toolchainCache) error {
+	path, err := cacheFilePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0600)
+}
+
+// invalidateCache removes the on-disk toolchains cache, if any, so that the
+// next List or Lookup call rebuilds it from a fresh walk of SrclibPath.
+// Callers that install, update, or remove a toolchain should call this
+// rather than waiting for cacheTTL to expire.
+func invalidateCache() error {
+	path, err := cacheFilePath()
+	if err != nil {
+		// No SrclibPath configured to cache under; nothing to invalidate.
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// cacheIsFresh reports whether c was built within the last cacheTTL. A
+// time-based check, rather than comparing mtimes of known entries or of the
+// SrclibPath roots, is what actually catches every way the set of installed
+// toolchains can change: toolchains are added and removed several
+// directories below a SrclibPath entry, so no single fixed-depth mtime
+// check sees every addition or removal.
+func cacheIsFresh(c *toolchainCache) bool {
+	return time.Since(c.BuiltAt) < cacheTTL
+}
+
+// buildCache performs the full walk (via the existing List logic) and
+// records the result.
+func buildCache() (*toolchainCache, error) {
+	found, err := listUncached()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &toolchainCache{BuiltAt: time.Now()}
+	for _, info := range found {
+		c.Entries = append(c.Entries, cacheEntry{Info: *info})
+	}
+
+	if err := writeCache(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
@@ -33,9 +33,31 @@ func init() {
 
 // Lookup finds a toolchain by path in the SRCLIBPATH. For each DIR in
 // SRCLIBPATH, it checks for the existence of DIR/PATH/Srclibtoolchain.
+//
+// Concurrent calls for the same path are collapsed into a single disk
+// access. Lookup first checks the on-disk toolchains cache (see List) and
+// only falls back to a fresh glob of SrclibPath on a cache miss.
 func Lookup(path string) (*Info, error) {
 	path = filepath.Clean(path)
 
+	v, err, _ := lookupGroup.Do(path, func() (interface{}, error) {
+		if c, err := readCache(); err == nil && cacheIsFresh(c) {
+			for _, e := range c.Entries {
+				if e.Info.Path == path {
+					info := e.Info
+					return &info, nil
+				}
+			}
+		}
+		return lookupUncached(path)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*Info), nil
+}
+
+func lookupUncached(path string) (*Info, error) {
 	matches, err := lookInPaths(filepath.Join(path, "Srclibtoolchain"), SrclibPath)
 	if err != nil {
 		return nil, err
@@ -50,8 +72,40 @@ func Lookup(path string) (*Info, error) {
 	return newInfo(path, filepath.Dir(matches[0]), "Srclibtoolchain")
 }
 
-// List finds all toolchains in the SRCLIBPATH.
+// List finds all toolchains in the SRCLIBPATH. Results are cached on disk
+// under $SRCLIBPATH[0]/.cache/toolchains.json; if the cache is still fresh
+// (see cacheIsFresh), List returns it directly instead of re-walking
+// SrclibPath. Concurrent calls are collapsed into a single walk/cache read.
 func List() ([]*Info, error) {
+	v, err, _ := listGroup.Do("", func() (interface{}, error) {
+		if c, err := readCache(); err == nil && cacheIsFresh(c) {
+			found := make([]*Info, len(c.Entries))
+			for i, e := range c.Entries {
+				info := e.Info
+				found[i] = &info
+			}
+			return found, nil
+		}
+		c, err := buildCache()
+		if err != nil {
+			return nil, err
+		}
+		found := make([]*Info, len(c.Entries))
+		for i, e := range c.Entries {
+			info := e.Info
+			found[i] = &info
+		}
+		return found, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]*Info), nil
+}
+
+// listUncached performs the full SrclibPath walk, bypassing the on-disk
+// cache. It is the target of buildCache, and of List on a cache miss.
+func listUncached() ([]*Info, error) {
 	var found []*Info
 	seen := map[string]string{}
 
@@ -123,12 +177,20 @@ func newInfo(toolchainPath, dir, srclibtoolchain string) (*Info, error) {
 		return nil, fmt.Errorf("installed toolchain program %q is not executable (+x)", prog)
 	}
 
+	var revision string
+	if meta, err := readRemoteMeta(dir); err == nil {
+		revision = meta.Revision
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
 	return &Info{
 		Path:                toolchainPath,
 		Dir:                 dir,
 		SrclibtoolchainFile: srclibtoolchain,
 		Program:             prog,
 		Dockerfile:          dockerfile,
+		Revision:            revision,
 	}, nil
 }
 
@@ -0,0 +1,16 @@
+package toolchain
+
+// Info describes a discovered or installed toolchain.
+type Info struct {
+	Path                string // import-path-style identifier, e.g. "github.com/user/srclib-go"
+	Dir                 string // directory containing the toolchain's Srclibtoolchain file
+	SrclibtoolchainFile string // base name of the Srclibtoolchain file found (e.g. "Srclibtoolchain")
+	Program             string // path (relative to Dir) of the built .bin/<name> program, if present
+	Dockerfile          string // base name of the Dockerfile found in Dir, if present
+
+	// Revision is the VCS revision this toolchain is pinned/updated to, if
+	// any. It is loaded from a Srclibtoolchain.lock.json sidecar (written by
+	// LookupOrFetch and Update) and is empty for toolchains that were never
+	// installed via either of those paths.
+	Revision string `json:",omitempty"`
+}
@@ -0,0 +1,202 @@
+package toolchain
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/sourcegraph/srclib/vcs"
+)
+
+// FetchOptions controls how LookupOrFetch is allowed to resolve a toolchain
+// that isn't already installed under SrclibPath.
+type FetchOptions struct {
+	// AllowNetwork permits LookupOrFetch to make network requests (repo-root
+	// discovery and cloning) to install a missing toolchain. It is off by
+	// default, mirroring `go get -insecure`'s default-deny stance: callers
+	// must opt in before srclib reaches out to arbitrary hosts.
+	AllowNetwork bool
+}
+
+// remoteMeta is the content of a toolchain directory's
+// Srclibtoolchain.lock.json sidecar. Revision records the revision that
+// LookupOrFetch pinned the toolchain to at install time, or that Update last
+// moved it to; newInfo reports it as Info.Revision. LookupOrFetch does not
+// read it back for a toolchain that is already installed.
+type remoteMeta struct {
+	Revision string `json:"Revision,omitempty"`
+}
+
+// LookupOrFetch behaves like Lookup, but if path is not found under any
+// SrclibPath entry, it resolves path as a Go-style import path (mirroring
+// the "go get" tool's vcs.RepoRootForImportPath), clones the toolchain's repository into
+// SrclibPath[0]/path, and returns the resulting Info.
+//
+// Network access (repo-root discovery and the clone itself) only happens if
+// opts.AllowNetwork is true; otherwise a missing toolchain is reported the
+// same way Lookup reports it, via os.ErrNotExist.
+func LookupOrFetch(path string, opts FetchOptions) (*Info, error) {
+	info, err := Lookup(path)
+	if err == nil || err != os.ErrNotExist || !opts.AllowNetwork {
+		return info, err
+	}
+
+	path = filepath.Clean(path)
+	if filepath.IsAbs(path) || path == ".." || strings.HasPrefix(path, "../") {
+		return nil, fmt.Errorf("toolchain: invalid toolchain path %q", path)
+	}
+
+	root, err := repoRootForImportPath(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := vcs.CheckSecure(root.cloneURL); err != nil {
+		// A malicious or compromised host's <meta> discovery response could
+		// otherwise redirect an install to a plaintext clone URL.
+		return nil, err
+	}
+
+	dirs := strings.Split(SrclibPath, ":")
+	if len(dirs) == 0 || dirs[0] == "" {
+		return nil, fmt.Errorf("toolchain: SRCLIBPATH is empty, cannot install %q", path)
+	}
+	destDir := filepath.Join(dirs[0], path)
+
+	// path is cleaned and rejected above if it is absolute or climbs above
+	// its own root (e.g. "github.com/u/r/../../../../etc" cleans to
+	// "../etc"), even though repoRootForImportPath's host patterns only
+	// constrain their first two segments. Check the joined destDir itself as
+	// well, so that a clone can never land outside dirs[0].
+	if rel, err := filepath.Rel(dirs[0], destDir); err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+		return nil, fmt.Errorf("toolchain: refusing to install %q outside of SRCLIBPATH", path)
+	}
+
+	if err := root.vcsCmd.Clone(root.cloneURL, destDir); err != nil {
+		return nil, fmt.Errorf("toolchain: fetching %q: %s", path, err)
+	}
+
+	if root.revision != "" {
+		if err := root.vcsCmd.TagSync(destDir, root.revision); err != nil {
+			return nil, fmt.Errorf("toolchain: pinning %q to %s: %s", path, root.revision, err)
+		}
+		if err := writeRemoteMeta(destDir, remoteMeta{Revision: root.revision}); err != nil {
+			return nil, err
+		}
+	}
+
+	// Make the newly installed toolchain visible to List/Lookup immediately,
+	// rather than waiting for the on-disk cache's TTL to expire.
+	if err := invalidateCache(); err != nil {
+		return nil, err
+	}
+
+	return newInfo(path, destDir, "Srclibtoolchain")
+}
+
+// repoRoot is the resolved location of a toolchain's source repository.
+type repoRoot struct {
+	vcsCmd   *vcs.Cmd
+	cloneURL string
+	revision string // pinned revision, if any was discovered (e.g. via gopkg.in's version suffix)
+}
+
+var (
+	githubPattern    = regexp.MustCompile(`^github\.com/([A-Za-z0-9_.-]+)/([A-Za-z0-9_.-]+)(/.*)?$`)
+	bitbucketPattern = regexp.MustCompile(`^bitbucket\.org/([A-Za-z0-9_.-]+)/([A-Za-z0-9_.-]+)(/.*)?$`)
+	gopkgPattern     = regexp.MustCompile(`^gopkg\.in/(?:([A-Za-z0-9_-]+)/)?([A-Za-z0-9_.-]+)\.v(\d+)(/.*)?$`)
+	metaTagPattern   = regexp.MustCompile(`<meta\s+name=["']srclib-toolchain["']\s+content=["'](\S+)\s+(\S+)["']\s*/?>`)
+)
+
+// repoRootForImportPath resolves an import path such as
+// "github.com/user/srclib-go" to the VCS backend and clone URL of its
+// repository, mirroring vcs.RepoRootForImportPath from the "go get" tool.
+// It recognizes github.com, bitbucket.org, and gopkg.in directly, and falls
+// back to fetching "https://{importPath}?go-get=1" and scanning for a
+// "<meta name=\"srclib-toolchain\" content=\"{vcs} {url}\">" tag.
+func repoRootForImportPath(importPath string) (*repoRoot, error) {
+	switch {
+	case githubPattern.MatchString(importPath):
+		m := githubPattern.FindStringSubmatch(importPath)
+		return &repoRoot{
+			vcsCmd:   vcs.ByName("git"),
+			cloneURL: fmt.Sprintf("https://github.com/%s/%s", m[1], m[2]),
+		}, nil
+
+	case bitbucketPattern.MatchString(importPath):
+		m := bitbucketPattern.FindStringSubmatch(importPath)
+		return &repoRoot{
+			vcsCmd:   vcs.ByName("git"),
+			cloneURL: fmt.Sprintf("https://bitbucket.org/%s/%s", m[1], m[2]),
+		}, nil
+
+	case gopkgPattern.MatchString(importPath):
+		m := gopkgPattern.FindStringSubmatch(importPath)
+		user := m[1]
+		if user == "" {
+			user = "go-" + m[2]
+		}
+		return &repoRoot{
+			vcsCmd:   vcs.ByName("git"),
+			cloneURL: fmt.Sprintf("https://github.com/%s/%s", user, m[2]),
+			revision: "v" + m[3],
+		}, nil
+	}
+
+	return discoverRepoRoot(importPath)
+}
+
+// discoverRepoRoot implements the generic <meta> discovery fallback: it
+// fetches "https://{importPath}?go-get=1" and looks for a tag of the form
+// <meta name="srclib-toolchain" content="{vcs} {url}">.
+func discoverRepoRoot(importPath string) (*repoRoot, error) {
+	resp, err := http.Get("https://" + importPath + "?go-get=1")
+	if err != nil {
+		return nil, fmt.Errorf("toolchain: discovering repo root for %q: %s", importPath, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	m := metaTagPattern.FindStringSubmatch(string(body))
+	if m == nil {
+		return nil, fmt.Errorf("toolchain: no srclib-toolchain meta tag found for import path %q", importPath)
+	}
+	vcsName, url := m[1], m[2]
+
+	vcsCmd := vcs.ByName(vcsName)
+	if vcsCmd == nil {
+		return nil, fmt.Errorf("toolchain: %q specifies unknown VCS %q", importPath, vcsName)
+	}
+	return &repoRoot{vcsCmd: vcsCmd, cloneURL: url}, nil
+}
+
+func writeRemoteMeta(toolchainDir string, meta remoteMeta) error {
+	b, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(toolchainDir, "Srclibtoolchain.lock.json"), b, 0600)
+}
+
+// readRemoteMeta reads the Srclibtoolchain.lock.json sidecar written by
+// writeRemoteMeta, if one exists. It returns an error satisfying
+// os.IsNotExist if toolchainDir has no lock file.
+func readRemoteMeta(toolchainDir string) (*remoteMeta, error) {
+	b, err := ioutil.ReadFile(filepath.Join(toolchainDir, "Srclibtoolchain.lock.json"))
+	if err != nil {
+		return nil, err
+	}
+	var meta remoteMeta
+	if err := json.Unmarshal(b, &meta); err != nil {
+		return nil, err
+	}
+	return &meta, nil
+}
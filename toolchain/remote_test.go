@@ -0,0 +1,73 @@
+package toolchain
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRepoRootForImportPath(t *testing.T) {
+	tests := []struct {
+		importPath   string
+		wantCloneURL string
+		wantRevision string
+	}{
+		{"github.com/user/srclib-go", "https://github.com/user/srclib-go", ""},
+		{"github.com/user/srclib-go/subpkg", "https://github.com/user/srclib-go", ""},
+		{"bitbucket.org/user/srclib-go", "https://bitbucket.org/user/srclib-go", ""},
+		{"gopkg.in/user/pkg.v2", "https://github.com/user/pkg", "v2"},
+		{"gopkg.in/pkg.v3", "https://github.com/go-pkg/pkg", "v3"},
+	}
+
+	for _, test := range tests {
+		root, err := repoRootForImportPath(test.importPath)
+		if err != nil {
+			t.Errorf("repoRootForImportPath(%q): %s", test.importPath, err)
+			continue
+		}
+		if root.cloneURL != test.wantCloneURL {
+			t.Errorf("repoRootForImportPath(%q).cloneURL = %q, want %q", test.importPath, root.cloneURL, test.wantCloneURL)
+		}
+		if root.revision != test.wantRevision {
+			t.Errorf("repoRootForImportPath(%q).revision = %q, want %q", test.importPath, root.revision, test.wantRevision)
+		}
+	}
+}
+
+// TestLookupOrFetch_RejectsPathTraversal ensures that a path which would
+// resolve (after repoRootForImportPath's host pattern matching, which only
+// constrains the first two segments) to a destDir outside of SrclibPath is
+// rejected before any clone is attempted.
+func TestLookupOrFetch_RejectsPathTraversal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "srclib-toolchain-traversal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	origSrclibPath := SrclibPath
+	SrclibPath = dir
+	defer func() { SrclibPath = origSrclibPath }()
+
+	tests := []string{
+		"github.com/user/repo/../../../../tmp/evil",
+		"../../etc/passwd",
+		"..",
+		"/etc/passwd",
+	}
+
+	for _, path := range tests {
+		_, err := LookupOrFetch(path, FetchOptions{AllowNetwork: true})
+		if err == nil {
+			t.Errorf("LookupOrFetch(%q): got nil error, want rejection of traversal/absolute path", path)
+			continue
+		}
+		if os.IsNotExist(err) {
+			t.Errorf("LookupOrFetch(%q): got os.ErrNotExist, want a path-rejection error (traversal check ran too late or not at all)", path)
+		}
+		if !strings.Contains(err.Error(), "toolchain") {
+			t.Errorf("LookupOrFetch(%q): got error %q, want it to come from LookupOrFetch's own validation", path, err)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package toolchain
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/sourcegraph/srclib/vcs"
+)
+
+// Update moves the toolchain installed at path to ref (a branch, tag, or
+// commit the toolchain's VCS backend understands) in place, and records the
+// resulting commit in a Srclibtoolchain.lock.json sidecar so that List can
+// report the installed revision without re-resolving it.
+//
+// Update requires that path already be installed (via Lookup or
+// LookupOrFetch); it updates the existing checkout rather than installing a
+// new one.
+//
+// Update does not rebuild the toolchain's .bin/<name> program: this package
+// has no build step of its own (building toolchains is driven by whatever
+// invokes srclib, typically via each toolchain's Dockerfile). To avoid
+// leaving a stale binary built from the old revision silently in place,
+// Update removes .bin/<name> if present, so that the returned Info reports
+// an empty Program until the caller rebuilds it.
+func Update(path, ref string) (*Info, error) {
+	info, err := Lookup(path)
+	if err != nil {
+		return nil, err
+	}
+
+	vcsCmd, dir, err := vcs.FromDir(info.Dir, info.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	cloneURL, err := vcsCmd.RemoteRepoURL(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := vcs.CheckSecure(cloneURL); err != nil {
+		return nil, err
+	}
+
+	commit, err := vcsCmd.CloneOrPull(cloneURL, dir, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := writeRemoteMeta(dir, remoteMeta{Revision: commit}); err != nil {
+		return nil, err
+	}
+
+	if info.Program != "" {
+		if err := os.Remove(filepath.Join(info.Dir, info.Program)); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	// Without this, List/Lookup would keep reporting the pre-update
+	// Revision and the just-deleted Program until the cache's TTL expires.
+	if err := invalidateCache(); err != nil {
+		return nil, err
+	}
+
+	return newInfo(info.Path, info.Dir, info.SrclibtoolchainFile)
+}
@@ -0,0 +1,35 @@
+package vcs
+
+import (
+	"os"
+)
+
+// CloneOrPull ensures that localPath holds a checkout of url at ref,
+// creating it with Clone if localPath does not yet exist, or updating it in
+// place with Download+TagSync otherwise. It returns the resulting revision
+// at localPath (as resolved by Revision). If ref is empty, TagSync is not
+// run: a fresh clone is left at the remote's default branch, and an existing
+// checkout is left at whatever revision Download leaves its working copy at
+// (for git and hg, whose Download does not touch the working copy, the
+// revision it was already at).
+func (v *Cmd) CloneOrPull(url, localPath, ref string) (commit string, err error) {
+	if _, err := os.Stat(localPath); os.IsNotExist(err) {
+		if err := v.Clone(url, localPath); err != nil {
+			return "", err
+		}
+	} else if err != nil {
+		return "", err
+	} else {
+		if err := v.Download(localPath); err != nil {
+			return "", err
+		}
+	}
+
+	if ref != "" {
+		if err := v.TagSync(localPath, ref); err != nil {
+			return "", err
+		}
+	}
+
+	return v.Revision(localPath)
+}
@@ -0,0 +1,70 @@
+package vcs
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SecureScheme lists the URL schemes considered safe to clone or fetch from:
+// they either encrypt the transport or run over a trusted local transport.
+// Modeled on cmd/go/internal/vcs's vcsCmd.isSecure.
+var SecureScheme = map[string]bool{
+	"https":   true,
+	"git+ssh": true,
+	"ssh":     true,
+	"bzr+ssh": true,
+	"svn+ssh": true,
+}
+
+// Insecure is a list of glob patterns (matched against a clone URL's host)
+// that are allowed to use an insecure scheme (plain "git://", "http://", or
+// "svn://") despite not appearing in SecureScheme. At init it is populated
+// from the comma-separated SRCLIB_INSECURE environment variable, and it is
+// empty (i.e. nothing is allowed) when that variable is unset. No
+// command-line flag sets it.
+var Insecure []string
+
+func init() {
+	if v := os.Getenv("SRCLIB_INSECURE"); v != "" {
+		Insecure = strings.Split(v, ",")
+	}
+}
+
+// InsecureSchemeError is returned by CheckSecure when a clone URL uses a
+// scheme that is not in SecureScheme and whose host does not match any entry
+// in Insecure. srclib runs toolchain code fetched from the resolved clone
+// URL, so silently allowing a plaintext scheme would let a MITM or DNS
+// attacker substitute arbitrary code.
+type InsecureSchemeError struct {
+	URL string
+}
+
+func (e *InsecureSchemeError) Error() string {
+	return fmt.Sprintf("vcs: insecure URL scheme in %q (set SRCLIB_INSECURE to allow this host)", e.URL)
+}
+
+// CheckSecure parses rawURL and returns an *InsecureSchemeError if its scheme
+// is not in SecureScheme and its host does not match any glob pattern in
+// Insecure. A rawURL that fails to parse, or that uses a scp-like
+// "user@host:path" git form (which is always over ssh), is treated as
+// secure.
+func CheckSecure(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme == "" {
+		// Not a URL with a scheme (e.g. the scp-like git@host:path form,
+		// which always goes over ssh) — nothing to check.
+		return nil
+	}
+	if SecureScheme[u.Scheme] {
+		return nil
+	}
+	for _, pattern := range Insecure {
+		if ok, _ := filepath.Match(pattern, u.Host); ok {
+			return nil
+		}
+	}
+	return &InsecureSchemeError{URL: rawURL}
+}
@@ -0,0 +1,49 @@
+package vcs
+
+import "testing"
+
+func TestCheckSecure(t *testing.T) {
+	tests := []struct {
+		url       string
+		insecure  []string
+		wantError bool
+	}{
+		{url: "https://github.com/user/repo"},
+		{url: "git+ssh://git@github.com/user/repo"},
+		{url: "ssh://git@example.com/repo"},
+		{url: "bzr+ssh://example.com/repo"},
+		{url: "svn+ssh://example.com/repo"},
+
+		// The scp-like "user@host:path" git form has no URL scheme at all,
+		// so CheckSecure has nothing to reject; it always goes over ssh.
+		{url: "git@github.com:user/repo.git"},
+
+		{url: "git://github.com/user/repo", wantError: true},
+		{url: "http://example.com/repo", wantError: true},
+		{url: "svn://example.com/repo", wantError: true},
+
+		// An insecure scheme is allowed once its host is on the allowlist...
+		{url: "git://insecure.example.com/repo", insecure: []string{"insecure.example.com"}},
+		// ...but only for hosts that actually match the allowlist pattern.
+		{url: "git://other.example.com/repo", insecure: []string{"insecure.example.com"}, wantError: true},
+		// Glob patterns are matched against the host, per filepath.Match.
+		{url: "http://build.internal/repo", insecure: []string{"*.internal"}},
+	}
+
+	for _, test := range tests {
+		Insecure = test.insecure
+		err := CheckSecure(test.url)
+		if test.wantError && err == nil {
+			t.Errorf("CheckSecure(%q) with Insecure=%v: got nil error, want InsecureSchemeError", test.url, test.insecure)
+		}
+		if !test.wantError && err != nil {
+			t.Errorf("CheckSecure(%q) with Insecure=%v: got error %v, want nil", test.url, test.insecure, err)
+		}
+		if test.wantError {
+			if _, ok := err.(*InsecureSchemeError); !ok {
+				t.Errorf("CheckSecure(%q): got error of type %T, want *InsecureSchemeError", test.url, err)
+			}
+		}
+	}
+	Insecure = nil
+}
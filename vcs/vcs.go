@@ -0,0 +1,244 @@
+// Package vcs provides a declarative registry of version control system
+// backends, modeled on the vcs.Cmd type used internally by cmd/go. Instead of
+// exec'ing a hard-coded list of VCS binaries and switching on a VCSType
+// string, callers register a Cmd describing how to detect, clone, and
+// resolve revisions for a given VCS, and look it up with FromDir.
+package vcs
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Cmd describes how to interact with a single version control system. It is
+// intentionally declarative: command lines are templates with {dir}, {url},
+// and {rev} placeholders, substituted and run with exec.Command rather than
+// going through a VCS-specific client library.
+type Cmd struct {
+	Name string // registry key, also reported as the VCS type, e.g. "git"
+	Cmd  string // name of the binary to invoke, e.g. "git"
+
+	// RootNames are marker files or directories (e.g. ".git", ".hg") that
+	// FromDir looks for when walking up from a directory to find a repo root.
+	RootNames []string
+
+	RemoteRepoCmd  []string // prints the configured clone URL; run with Dir={dir}
+	ResolveRepoCmd []string // alternate command to resolve a clone URL, if RemoteRepoCmd fails or is empty
+
+	RevisionCmd []string // prints the current revision/commit ID; run with Dir={dir}
+	TagSyncCmd  []string // updates the working copy to {rev}; run with Dir={dir}
+	DownloadCmd []string // fetches upstream changes without updating the working copy; run with Dir={dir}
+	CloneCmd    []string // clones {url} into {dir}
+}
+
+// registry of known VCS backends, keyed by Name.
+var registry = make(map[string]*Cmd)
+
+// Register adds cmd to the set of known VCS backends. It is typically called
+// from an init function. Registering a Cmd with a Name that is already
+// registered replaces the existing entry.
+func Register(cmd *Cmd) {
+	registry[cmd.Name] = cmd
+}
+
+// Registered returns all registered VCS backends, in unspecified order.
+func Registered() []*Cmd {
+	cmds := make([]*Cmd, 0, len(registry))
+	for _, cmd := range registry {
+		cmds = append(cmds, cmd)
+	}
+	return cmds
+}
+
+// ByName returns the registered Cmd with the given Name, or nil if none is
+// registered.
+func ByName(name string) *Cmd {
+	return registry[name]
+}
+
+func init() {
+	Register(vcsGit)
+	Register(vcsHg)
+	Register(vcsSvn)
+	Register(vcsBzr)
+}
+
+var (
+	vcsGit = &Cmd{
+		Name:      "git",
+		Cmd:       "git",
+		RootNames: []string{".git"},
+
+		RemoteRepoCmd: []string{"config", "remote.origin.url"},
+
+		RevisionCmd: []string{"rev-parse", "HEAD"},
+		TagSyncCmd:  []string{"checkout", "{rev}"},
+		DownloadCmd: []string{"fetch"},
+		CloneCmd:    []string{"clone", "{url}", "{dir}"},
+	}
+
+	vcsHg = &Cmd{
+		Name:      "hg",
+		Cmd:       "hg",
+		RootNames: []string{".hg"},
+
+		RemoteRepoCmd: []string{"paths", "default"},
+
+		// "hg id -i" appends a trailing "+" to the node id when the working
+		// copy is dirty, which would corrupt anything keyed off CommitID
+		// (e.g. buildstore's commit-path cache). "hg log -r . --template
+		// {node}" always yields the clean full node id of the current
+		// working copy parent, dirty or not.
+		RevisionCmd: []string{"log", "-r", ".", "--template", "{node}"},
+		TagSyncCmd:  []string{"update", "-r", "{rev}"},
+		DownloadCmd: []string{"pull"},
+		CloneCmd:    []string{"clone", "{url}", "{dir}"},
+	}
+
+	vcsSvn = &Cmd{
+		Name:      "svn",
+		Cmd:       "svn",
+		RootNames: []string{".svn"},
+
+		RemoteRepoCmd: []string{"info", "--show-item", "url"},
+
+		RevisionCmd: []string{"info", "--show-item", "revision"},
+		TagSyncCmd:  []string{"update", "-r", "{rev}"},
+		DownloadCmd: []string{"update"},
+		CloneCmd:    []string{"checkout", "{url}", "{dir}"},
+	}
+
+	vcsBzr = &Cmd{
+		Name:      "bzr",
+		Cmd:       "bzr",
+		RootNames: []string{".bzr"},
+
+		RemoteRepoCmd: []string{"config", "parent_location"},
+
+		RevisionCmd: []string{"revno"},
+		TagSyncCmd:  []string{"update", "-r", "{rev}"},
+		DownloadCmd: []string{"pull"},
+		CloneCmd:    []string{"branch", "{url}", "{dir}"},
+	}
+)
+
+// FromDir walks up from dir, looking for a directory containing one of a
+// registered Cmd's RootNames, and returns the matching Cmd along with the
+// repository root it found. It stops (without matching) once it walks above
+// srcRoot, or at the filesystem root if srcRoot is empty.
+func FromDir(dir, srcRoot string) (*Cmd, string, error) {
+	dir = filepath.Clean(dir)
+	origDir := dir
+	for {
+		for _, cmd := range registry {
+			for _, rootName := range cmd.RootNames {
+				if isDirOrFile(filepath.Join(dir, rootName)) {
+					return cmd, dir, nil
+				}
+			}
+		}
+		if dir == srcRoot {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return nil, "", fmt.Errorf("vcs: directory %q is not using a known version control system (looked up to %q)", origDir, srcRoot)
+}
+
+func isDirOrFile(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// substitute replaces {dir}, {url}, and {rev} placeholders in args with the
+// corresponding value. An empty value replaces its placeholder with "".
+func substitute(args []string, dir, url, rev string) []string {
+	out := make([]string, len(args))
+	for i, a := range args {
+		a = strings.Replace(a, "{dir}", dir, -1)
+		a = strings.Replace(a, "{url}", url, -1)
+		a = strings.Replace(a, "{rev}", rev, -1)
+		out[i] = a
+	}
+	return out
+}
+
+// run runs cmd.Cmd with args (after {dir}/{url}/{rev} substitution) in dir,
+// and returns its trimmed stdout.
+func (v *Cmd) run(dir string, args []string, url, rev string) (string, error) {
+	cmd := exec.Command(v.Cmd, substitute(args, dir, url, rev)...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%s %s: %s", v.Cmd, strings.Join(args, " "), err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// RemoteRepoURL returns the configured clone URL for the repository at dir,
+// running ResolveRepoCmd as a fallback if RemoteRepoCmd fails or is unset.
+func (v *Cmd) RemoteRepoURL(dir string) (string, error) {
+	if len(v.RemoteRepoCmd) > 0 {
+		if url, err := v.run(dir, v.RemoteRepoCmd, "", ""); err == nil {
+			return url, nil
+		} else if len(v.ResolveRepoCmd) == 0 {
+			return "", err
+		}
+	}
+	if len(v.ResolveRepoCmd) > 0 {
+		return v.run(dir, v.ResolveRepoCmd, "", "")
+	}
+	return "", fmt.Errorf("vcs: %s has no RemoteRepoCmd or ResolveRepoCmd", v.Name)
+}
+
+// Revision returns the currently checked-out revision/commit ID of the
+// repository at dir.
+func (v *Cmd) Revision(dir string) (string, error) {
+	if len(v.RevisionCmd) == 0 {
+		return "", fmt.Errorf("vcs: %s has no RevisionCmd", v.Name)
+	}
+	return v.run(dir, v.RevisionCmd, "", "")
+}
+
+// TagSync updates the working copy at dir to rev.
+func (v *Cmd) TagSync(dir, rev string) error {
+	if len(v.TagSyncCmd) == 0 {
+		return fmt.Errorf("vcs: %s has no TagSyncCmd", v.Name)
+	}
+	_, err := v.run(dir, v.TagSyncCmd, "", rev)
+	return err
+}
+
+// Download fetches upstream changes into the repository at dir, without
+// altering the working copy.
+func (v *Cmd) Download(dir string) error {
+	if len(v.DownloadCmd) == 0 {
+		return fmt.Errorf("vcs: %s has no DownloadCmd", v.Name)
+	}
+	_, err := v.run(dir, v.DownloadCmd, "", "")
+	return err
+}
+
+// Clone clones url into dir, which must not yet exist.
+func (v *Cmd) Clone(url, dir string) error {
+	if len(v.CloneCmd) == 0 {
+		return fmt.Errorf("vcs: %s has no CloneCmd", v.Name)
+	}
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return err
+	}
+	cmd := exec.Command(v.Cmd, substitute(v.CloneCmd, dir, url, "")...)
+	cmd.Dir = filepath.Dir(dir)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s clone %s: %s\n%s", v.Cmd, url, err, out)
+	}
+	return nil
+}